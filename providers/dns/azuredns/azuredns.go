@@ -0,0 +1,345 @@
+// Package azuredns implements a DNS provider for solving the DNS-01 challenge using azure DNS.
+// Azure doesn't like trailing dots on domain names, most of the acme code does.
+package azuredns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/go-acme/lego/v4/platform/config/env"
+)
+
+// Environment variables names.
+const (
+	envNamespace = "AZURE_"
+
+	EnvEnvironment    = envNamespace + "ENVIRONMENT"
+	EnvSubscriptionID = envNamespace + "SUBSCRIPTION_ID"
+	EnvResourceGroup  = envNamespace + "RESOURCE_GROUP"
+	EnvPrivateZone    = envNamespace + "PRIVATE_ZONE"
+
+	EnvTenantID     = envNamespace + "TENANT_ID"
+	EnvClientID     = envNamespace + "CLIENT_ID"
+	EnvClientSecret = envNamespace + "CLIENT_SECRET"
+
+	EnvTTL                = envNamespace + "TTL"
+	EnvPropagationTimeout = envNamespace + "PROPAGATION_TIMEOUT"
+	EnvPollingInterval    = envNamespace + "POLLING_INTERVAL"
+
+	// serviceDiscoveryEnvNamespace groups the environment variables added for dynamic, multi-zone
+	// discovery, to set them apart from the single-zone settings above.
+	serviceDiscoveryEnvNamespace = envNamespace + "DNS_"
+
+	EnvZoneDiscoveryMethod       = serviceDiscoveryEnvNamespace + "ZONE_DISCOVERY_METHOD"
+	EnvServiceDiscoveryFilter    = serviceDiscoveryEnvNamespace + "SERVICE_DISCOVERY_FILTER"
+	EnvTenants                   = serviceDiscoveryEnvNamespace + "TENANTS"
+	EnvChallengeCNAMEDelegation  = serviceDiscoveryEnvNamespace + "CHALLENGE_CNAME_DELEGATION"
+	EnvChallengeCNAMETargetZones = serviceDiscoveryEnvNamespace + "CHALLENGE_CNAME_TARGET_ZONES"
+)
+
+// Config is used to configure the creation of the DNSProvider.
+type Config struct {
+	SubscriptionID string
+	ResourceGroup  string
+	PrivateZone    bool
+
+	Environment cloud.Configuration
+
+	// optional if using default Azure credentials
+	ClientID     string
+	ClientSecret string
+	TenantID     string
+
+	PropagationTimeout time.Duration
+	PollingInterval    time.Duration
+	TTL                int
+
+	// ZoneDiscoveryMethod selects how zones are enumerated (see the ZoneDiscoveryMethod* constants).
+	ZoneDiscoveryMethod string
+	// ServiceDiscoveryFilter narrows zone discovery: a Kusto "where ..." clause on the Resource
+	// Graph path (the leading "|" is added by the query builder), or a "key=value" tag filter on the
+	// list-based fallback path.
+	ServiceDiscoveryFilter string
+
+	// SubscriptionTenants overrides the tenant used to query an individual subscription, keyed by
+	// subscription ID. Populated from AZURE_DNS_TENANTS.
+	SubscriptionTenants map[string]string
+	// TenantCredentials holds the credential to use for a given tenant ID, populated from
+	// SubscriptionTenants at provider construction time.
+	TenantCredentials map[string]azcore.TokenCredential
+
+	// ChallengeCNAMEDelegationEnabled turns on CNAME-delegation for every domain: the
+	// _acme-challenge record is resolved and the TXT record is written on whatever zone its CNAME
+	// target resolves to, instead of domain's own zone. ChallengeCNAMETargetZones overrides this
+	// per domain, pinning the exact target name (the live CNAME value) so the lookup - which can be
+	// slow or unreliable right after the CNAME record was created - is skipped entirely.
+	ChallengeCNAMEDelegationEnabled bool
+	ChallengeCNAMETargetZones       map[string]string
+}
+
+// NewDefaultConfig returns a default configuration for the DNSProvider.
+func NewDefaultConfig() *Config {
+	return &Config{
+		TTL:                 env.GetOrDefaultInt(EnvTTL, 60),
+		PropagationTimeout:  env.GetOrDefaultSecond(EnvPropagationTimeout, 2*time.Minute),
+		PollingInterval:     env.GetOrDefaultSecond(EnvPollingInterval, 2*time.Second),
+		Environment:         cloud.AzurePublic,
+		ZoneDiscoveryMethod: ZoneDiscoveryMethodResourceGraph,
+	}
+}
+
+// DNSProvider implements the challenge.Provider interface.
+type DNSProvider struct {
+	config      *Config
+	credentials azcore.TokenCredential
+	clients     *dnsZoneClientCache
+}
+
+// NewDNSProvider returns a DNSProvider instance configured for azuredns.
+func NewDNSProvider() (*DNSProvider, error) {
+	config := NewDefaultConfig()
+
+	environmentName := env.GetOrFile(EnvEnvironment)
+	if environmentName != "" {
+		switch environmentName {
+		case "china":
+			config.Environment = cloud.AzureChina
+		case "public":
+			config.Environment = cloud.AzurePublic
+		case "usgovernment":
+			config.Environment = cloud.AzureGovernment
+		default:
+			return nil, fmt.Errorf("azuredns: unknown environment %s", environmentName)
+		}
+	}
+
+	config.SubscriptionID = env.GetOrFile(EnvSubscriptionID)
+	config.ResourceGroup = env.GetOrFile(EnvResourceGroup)
+	config.PrivateZone = env.GetOrDefaultBool(EnvPrivateZone, false)
+
+	config.ClientID = env.GetOrFile(EnvClientID)
+	config.ClientSecret = env.GetOrFile(EnvClientSecret)
+	config.TenantID = env.GetOrFile(EnvTenantID)
+
+	config.ZoneDiscoveryMethod = env.GetOrDefaultString(EnvZoneDiscoveryMethod, ZoneDiscoveryMethodResourceGraph)
+	config.ServiceDiscoveryFilter = env.GetOrFile(EnvServiceDiscoveryFilter)
+	config.SubscriptionTenants = parseKeyValueList(env.GetOrFile(EnvTenants))
+	config.ChallengeCNAMEDelegationEnabled = env.GetOrDefaultBool(EnvChallengeCNAMEDelegation, false)
+	config.ChallengeCNAMETargetZones = parseKeyValueList(env.GetOrFile(EnvChallengeCNAMETargetZones))
+
+	return NewDNSProviderConfig(config)
+}
+
+// NewDNSProviderConfig return a DNSProvider instance configured for Azure.
+func NewDNSProviderConfig(config *Config) (*DNSProvider, error) {
+	if config == nil {
+		return nil, errors.New("azuredns: the configuration of the DNS provider is nil")
+	}
+
+	switch config.ZoneDiscoveryMethod {
+	case "", ZoneDiscoveryMethodResourceGraph, ZoneDiscoveryMethodListBySubscription, ZoneDiscoveryMethodAuto:
+	default:
+		return nil, fmt.Errorf("azuredns: unknown zone discovery method %s", config.ZoneDiscoveryMethod)
+	}
+
+	credentials, err := newCredential(config, config.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// SubscriptionID is not required: an empty value means "every subscription credentials can see",
+	// which both discoverDnsZonesByResourceGraph and discoverDnsZonesByList already handle.
+	config.TenantCredentials, err = tenantCredentialsForSubscriptions(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DNSProvider{
+		config:      config,
+		credentials: credentials,
+		clients:     newDnsZoneClientCache(config, credentials),
+	}, nil
+}
+
+// newCredential builds the azcore.TokenCredential to use for tenantID: a client-secret credential if
+// config.ClientID/ClientSecret are set, otherwise the ambient DefaultAzureCredential scoped to tenantID.
+func newCredential(config *Config, tenantID string) (azcore.TokenCredential, error) {
+	if config.ClientID != "" && config.ClientSecret != "" {
+		options := azidentity.ClientSecretCredentialOptions{
+			ClientOptions: azcore.ClientOptions{
+				Cloud: config.Environment,
+			},
+		}
+
+		credentials, err := azidentity.NewClientSecretCredential(tenantID, config.ClientID, config.ClientSecret, &options)
+		if err != nil {
+			return nil, fmt.Errorf("azuredns: %w", err)
+		}
+
+		return credentials, nil
+	}
+
+	options := azidentity.DefaultAzureCredentialOptions{
+		ClientOptions: azcore.ClientOptions{
+			Cloud: config.Environment,
+		},
+		TenantID: tenantID,
+	}
+
+	credentials, err := azidentity.NewDefaultAzureCredential(&options)
+	if err != nil {
+		return nil, fmt.Errorf("azuredns: %w", err)
+	}
+
+	return credentials, nil
+}
+
+// tenantCredentialsForSubscriptions builds a credential per distinct tenant ID referenced by
+// config.SubscriptionTenants, so discoverDnsZones and the record-set clients can authenticate
+// against each subscription's own tenant. Subscriptions without an override keep using the
+// provider's default credential. newCredential re-issues a DefaultAzureCredential scoped to
+// tenantID just as readily as a client-secret credential, so this runs regardless of which
+// credential kind the provider is using.
+func tenantCredentialsForSubscriptions(config *Config) (map[string]azcore.TokenCredential, error) {
+	tenantCredentials := map[string]azcore.TokenCredential{}
+	for _, tenantID := range config.SubscriptionTenants {
+		if tenantID == "" || tenantCredentials[tenantID] != nil {
+			continue
+		}
+
+		credential, err := newCredential(config, tenantID)
+		if err != nil {
+			return nil, err
+		}
+
+		tenantCredentials[tenantID] = credential
+	}
+
+	return tenantCredentials, nil
+}
+
+// parseKeyValueList parses a comma-separated list of "key=value" pairs, as used by AZURE_DNS_TENANTS
+// and AZURE_DNS_CHALLENGE_CNAME_TARGET_ZONES. Malformed entries (missing "=", empty key) are skipped.
+func parseKeyValueList(raw string) map[string]string {
+	if raw == "" {
+		return nil
+	}
+
+	values := map[string]string{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if key == "" {
+			continue
+		}
+
+		values[key] = value
+	}
+
+	return values
+}
+
+// Timeout returns the timeout and interval to use when checking for DNS propagation.
+// Adjusting here to cope with spikes in propagation times.
+func (d *DNSProvider) Timeout() (timeout, interval time.Duration) {
+	return d.config.PropagationTimeout, d.config.PollingInterval
+}
+
+// Present creates a TXT record to fulfill the dns-01 challenge.
+func (d *DNSProvider) Present(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	info := dns01.GetChallengeInfo(domain, keyAuth)
+
+	zones, err := discoverDnsZones(ctx, d.config, d.credentials)
+	if err != nil {
+		return fmt.Errorf("azuredns: %w", err)
+	}
+
+	zone, fqdn, err := d.resolveZone(zones, domain, info.EffectiveFQDN)
+	if err != nil {
+		return err
+	}
+
+	subDomain, err := dns01.ExtractSubDomain(fqdn, zone.Name)
+	if err != nil {
+		return fmt.Errorf("azuredns: %w", err)
+	}
+
+	if d.config.PrivateZone {
+		return d.presentPrivate(ctx, zone, subDomain, info.Value)
+	}
+
+	return d.presentPublic(ctx, zone, subDomain, info.Value)
+}
+
+// CleanUp removes the TXT record matching the specified parameters.
+func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	info := dns01.GetChallengeInfo(domain, keyAuth)
+
+	zones, err := discoverDnsZones(ctx, d.config, d.credentials)
+	if err != nil {
+		return fmt.Errorf("azuredns: %w", err)
+	}
+
+	zone, fqdn, err := d.resolveZone(zones, domain, info.EffectiveFQDN)
+	if err != nil {
+		return err
+	}
+
+	subDomain, err := dns01.ExtractSubDomain(fqdn, zone.Name)
+	if err != nil {
+		return fmt.Errorf("azuredns: %w", err)
+	}
+
+	if d.config.PrivateZone {
+		return d.cleanupPrivate(ctx, zone, subDomain)
+	}
+
+	return d.cleanupPublic(ctx, zone, subDomain)
+}
+
+// resolveZone picks the zone and FQDN that Present/CleanUp should write to for domain: the
+// CNAME-delegated target if challengeCNAMEDelegationEnabled, otherwise the zone in zones that
+// longest-suffix-matches effectiveFQDN.
+func (d *DNSProvider) resolveZone(zones map[string]ServiceDiscoveryZone, domain, effectiveFQDN string) (ServiceDiscoveryZone, string, error) {
+	target, ok, err := resolveChallengeAliasTarget(d.config, zones, domain)
+	if err != nil {
+		return ServiceDiscoveryZone{}, "", err
+	}
+	if ok {
+		return target.Zone, target.FQDN, nil
+	}
+
+	zone, found := ResolveZoneForFQDN(zones, effectiveFQDN)
+	if !found {
+		return ServiceDiscoveryZone{}, "", fmt.Errorf("azuredns: no visible zone matches %q", effectiveFQDN)
+	}
+
+	return zone, effectiveFQDN, nil
+}
+
+func deref[T string | int | int32 | int64](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+
+	return *v
+}
@@ -0,0 +1,94 @@
+package azuredns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ChallengeAliasTarget describes where Present/CleanUp should write (or remove) the
+// `_acme-challenge` TXT record when CNAME delegation is configured for a domain.
+type ChallengeAliasTarget struct {
+	FQDN string
+	Zone ServiceDiscoveryZone
+}
+
+// resolveChallengeAliasTarget implements the well-known ACME-DNS / CNAME-delegation pattern:
+// if domain has delegation configured (AZURE_DNS_CHALLENGE_CNAME_DELEGATION, or a per-domain
+// override in config.ChallengeCNAMETargetZones pinning the exact target name), the TXT record is
+// written under the resolved target name instead of under domain's own zone. Present and CleanUp
+// must call this symmetrically, so a challenge written to the delegated zone is also removed from it.
+func resolveChallengeAliasTarget(config *Config, zones map[string]ServiceDiscoveryZone, domain string) (target ChallengeAliasTarget, ok bool, err error) {
+	if !challengeCNAMEDelegationEnabled(config, domain) {
+		return ChallengeAliasTarget{}, false, nil
+	}
+
+	// A per-domain override pins the target name directly - the exact name the live CNAME lookup
+	// below would otherwise have resolved - so that lookup, which can be slow or unreliable right
+	// after the CNAME record was created, is skipped entirely.
+	if targetName, ok := config.ChallengeCNAMETargetZones[domain]; ok && targetName != "" {
+		zone, found := ResolveZoneForFQDN(zones, targetName)
+		if !found {
+			return ChallengeAliasTarget{}, false, fmt.Errorf("azuredns: domain %q is pinned to delegated target %q but no visible zone matches it", domain, targetName)
+		}
+
+		return ChallengeAliasTarget{FQDN: targetName, Zone: zone}, true, nil
+	}
+
+	challengeFQDN := fmt.Sprintf("_acme-challenge.%s.", strings.TrimSuffix(domain, "."))
+
+	cname, err := net.LookupCNAME(challengeFQDN)
+	if err != nil {
+		return ChallengeAliasTarget{}, false, fmt.Errorf("azuredns: could not resolve CNAME for %q: %w", challengeFQDN, err)
+	}
+
+	if strings.EqualFold(cname, challengeFQDN) {
+		// no CNAME in place, nothing to delegate to
+		return ChallengeAliasTarget{}, false, nil
+	}
+
+	zone, found := ResolveZoneForFQDN(zones, cname)
+	if !found {
+		return ChallengeAliasTarget{}, false, fmt.Errorf("azuredns: domain %q delegates to %q but no visible zone matches it", domain, cname)
+	}
+
+	return ChallengeAliasTarget{FQDN: cname, Zone: zone}, true, nil
+}
+
+// challengeCNAMEDelegationEnabled reports whether domain has CNAME delegation configured, either
+// via the per-domain override map or the global AZURE_DNS_CHALLENGE_CNAME_DELEGATION switch.
+func challengeCNAMEDelegationEnabled(config *Config, domain string) bool {
+	if targetName, ok := config.ChallengeCNAMETargetZones[domain]; ok && targetName != "" {
+		return true
+	}
+
+	return config.ChallengeCNAMEDelegationEnabled
+}
+
+// ResolveZoneForFQDN returns the zone in zones (as returned by discoverDnsZones) whose name is the
+// longest suffix match of fqdn. zones is keyed by the fully-qualified "subscriptionId/resourceGroup/name"
+// so the same zone name can legitimately appear more than once; matching is done on zone.Name, not the
+// map key. This is what Present/CleanUp use to pick the right zone for a challenge FQDN. Ties (the same
+// zone name visible in more than one subscription/resource group) are broken deterministically by map
+// key, so repeated calls - e.g. from Present and the later matching CleanUp - always agree.
+func ResolveZoneForFQDN(zones map[string]ServiceDiscoveryZone, fqdn string) (ServiceDiscoveryZone, bool) {
+	fqdn = strings.TrimSuffix(strings.ToLower(fqdn), ".")
+
+	var bestKey string
+	var best ServiceDiscoveryZone
+	found := false
+	for key, zone := range zones {
+		zoneName := strings.TrimSuffix(strings.ToLower(zone.Name), ".")
+		if fqdn != zoneName && !strings.HasSuffix(fqdn, "."+zoneName) {
+			continue
+		}
+
+		bestZoneName := strings.TrimSuffix(strings.ToLower(best.Name), ".")
+		isBetter := !found || len(zoneName) > len(bestZoneName) || (len(zoneName) == len(bestZoneName) && key < bestKey)
+		if isBetter {
+			best, bestKey, found = zone, key, true
+		}
+	}
+
+	return best, found
+}
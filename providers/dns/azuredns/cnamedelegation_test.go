@@ -0,0 +1,164 @@
+package azuredns
+
+import "testing"
+
+func TestChallengeCNAMEDelegationEnabled(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *Config
+		domain string
+		want   bool
+	}{
+		{
+			desc:   "disabled by default",
+			config: &Config{},
+			domain: "example.com",
+			want:   false,
+		},
+		{
+			desc:   "enabled globally",
+			config: &Config{ChallengeCNAMEDelegationEnabled: true},
+			domain: "example.com",
+			want:   true,
+		},
+		{
+			desc:   "enabled for an unrelated domain does not enable this one",
+			config: &Config{ChallengeCNAMETargetZones: map[string]string{"other.com": "foo.challenge.example.com"}},
+			domain: "example.com",
+			want:   false,
+		},
+		{
+			desc:   "pinned per-domain override enables it regardless of the global switch",
+			config: &Config{ChallengeCNAMETargetZones: map[string]string{"example.com": "foo.challenge.example.com"}},
+			domain: "example.com",
+			want:   true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			got := challengeCNAMEDelegationEnabled(test.config, test.domain)
+			if got != test.want {
+				t.Errorf("challengeCNAMEDelegationEnabled() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResolveChallengeAliasTargetPinned(t *testing.T) {
+	zones := map[string]ServiceDiscoveryZone{
+		"sub1/rg1/challenge.example.com": {Name: "challenge.example.com", SubscriptionID: "sub1", ResourceGroup: "rg1"},
+	}
+
+	t.Run("not configured returns ok=false", func(t *testing.T) {
+		target, ok, err := resolveChallengeAliasTarget(&Config{}, zones, "example.com")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ok {
+			t.Fatalf("expected ok=false, got target %+v", target)
+		}
+	})
+
+	t.Run("pinned name resolves to the zone that contains it", func(t *testing.T) {
+		config := &Config{ChallengeCNAMETargetZones: map[string]string{"example.com": "foo.challenge.example.com"}}
+
+		target, ok, err := resolveChallengeAliasTarget(config, zones, "example.com")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !ok {
+			t.Fatal("expected ok=true")
+		}
+		if target.FQDN != "foo.challenge.example.com" {
+			t.Errorf("target.FQDN = %q, want %q", target.FQDN, "foo.challenge.example.com")
+		}
+		if target.Zone.Name != "challenge.example.com" {
+			t.Errorf("target.Zone.Name = %q, want %q", target.Zone.Name, "challenge.example.com")
+		}
+
+		// The target FQDN must actually be a subdomain of the resolved zone, or
+		// dns01.ExtractSubDomain (called by Present/CleanUp) will fail.
+		if target.FQDN == target.Zone.Name {
+			t.Fatal("target.FQDN must be a subdomain of target.Zone.Name, not equal to it")
+		}
+	})
+
+	t.Run("pinned name with no matching zone errors", func(t *testing.T) {
+		config := &Config{ChallengeCNAMETargetZones: map[string]string{"example.com": "foo.unknown.com"}}
+
+		_, ok, err := resolveChallengeAliasTarget(config, zones, "example.com")
+		if ok {
+			t.Fatal("expected ok=false")
+		}
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestResolveZoneForFQDN(t *testing.T) {
+	zones := map[string]ServiceDiscoveryZone{
+		"sub1/rg1/example.com":           {Name: "example.com", SubscriptionID: "sub1", ResourceGroup: "rg1"},
+		"sub1/rg1/challenge.example.com": {Name: "challenge.example.com", SubscriptionID: "sub1", ResourceGroup: "rg1"},
+	}
+
+	testCases := []struct {
+		desc     string
+		fqdn     string
+		wantName string
+		wantOK   bool
+	}{
+		{
+			desc:     "matches the more specific delegated zone",
+			fqdn:     "_acme-challenge.foo.challenge.example.com.",
+			wantName: "challenge.example.com",
+			wantOK:   true,
+		},
+		{
+			desc:     "falls back to the apex zone",
+			fqdn:     "_acme-challenge.foo.example.com.",
+			wantName: "example.com",
+			wantOK:   true,
+		},
+		{
+			desc:   "no zone matches",
+			fqdn:   "_acme-challenge.foo.other.com.",
+			wantOK: false,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			zone, ok := ResolveZoneForFQDN(zones, test.fqdn)
+			if ok != test.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, test.wantOK)
+			}
+			if ok && zone.Name != test.wantName {
+				t.Errorf("zone.Name = %q, want %q", zone.Name, test.wantName)
+			}
+		})
+	}
+}
+
+func TestResolveZoneForFQDNTieBreakIsDeterministic(t *testing.T) {
+	zones := map[string]ServiceDiscoveryZone{
+		"sub1/rg1/example.com": {Name: "example.com", SubscriptionID: "sub1", ResourceGroup: "rg1"},
+		"sub2/rg2/example.com": {Name: "example.com", SubscriptionID: "sub2", ResourceGroup: "rg2"},
+	}
+
+	var first ServiceDiscoveryZone
+	for i := 0; i < 20; i++ {
+		zone, ok := ResolveZoneForFQDN(zones, "foo.example.com.")
+		if !ok {
+			t.Fatal("expected a match")
+		}
+		if i == 0 {
+			first = zone
+			continue
+		}
+		if zone != first {
+			t.Fatalf("tie-break was not deterministic: got %+v, previously %+v", zone, first)
+		}
+	}
+}
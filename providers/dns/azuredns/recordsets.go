@@ -0,0 +1,142 @@
+package azuredns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+)
+
+// presentPublic creates or updates the TXT record set subDomain in zone (a public DNS zone),
+// merging value into whatever TXT values are already present so concurrent challenges for the
+// same subdomain don't clobber each other.
+func (d *DNSProvider) presentPublic(ctx context.Context, zone ServiceDiscoveryZone, subDomain, value string) error {
+	client, err := d.clients.RecordSetsClient(zone)
+	if err != nil {
+		return fmt.Errorf("azuredns: %w", err)
+	}
+
+	existing, err := client.Get(ctx, zone.ResourceGroup, zone.Name, subDomain, armdns.RecordTypeTXT, nil)
+	if err != nil {
+		var respErr *azcore.ResponseError
+		if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("azuredns: %w", err)
+		}
+	}
+
+	ttl := int64(d.config.TTL)
+	rec := armdns.RecordSet{
+		Properties: &armdns.RecordSetProperties{
+			TTL:        &ttl,
+			TxtRecords: mergePublicTxtRecords(existing.Properties, value),
+		},
+	}
+
+	if _, err := client.CreateOrUpdate(ctx, zone.ResourceGroup, zone.Name, subDomain, armdns.RecordTypeTXT, rec, nil); err != nil {
+		return fmt.Errorf("azuredns: %w", err)
+	}
+
+	return nil
+}
+
+// cleanupPublic removes the TXT record set subDomain from zone (a public DNS zone).
+func (d *DNSProvider) cleanupPublic(ctx context.Context, zone ServiceDiscoveryZone, subDomain string) error {
+	client, err := d.clients.RecordSetsClient(zone)
+	if err != nil {
+		return fmt.Errorf("azuredns: %w", err)
+	}
+
+	if _, err := client.Delete(ctx, zone.ResourceGroup, zone.Name, subDomain, armdns.RecordTypeTXT, nil); err != nil {
+		return fmt.Errorf("azuredns: %w", err)
+	}
+
+	return nil
+}
+
+// presentPrivate creates or updates the TXT record set subDomain in zone (a private DNS zone).
+func (d *DNSProvider) presentPrivate(ctx context.Context, zone ServiceDiscoveryZone, subDomain, value string) error {
+	client, err := d.clients.PrivateRecordSetsClient(zone)
+	if err != nil {
+		return fmt.Errorf("azuredns: %w", err)
+	}
+
+	existing, err := client.Get(ctx, zone.ResourceGroup, zone.Name, armprivatedns.RecordTypeTXT, subDomain, nil)
+	if err != nil {
+		var respErr *azcore.ResponseError
+		if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("azuredns: %w", err)
+		}
+	}
+
+	ttl := int64(d.config.TTL)
+	rec := armprivatedns.RecordSet{
+		Properties: &armprivatedns.RecordSetProperties{
+			TTL:        &ttl,
+			TxtRecords: mergePrivateTxtRecords(existing.Properties, value),
+		},
+	}
+
+	if _, err := client.CreateOrUpdate(ctx, zone.ResourceGroup, zone.Name, armprivatedns.RecordTypeTXT, subDomain, rec, nil); err != nil {
+		return fmt.Errorf("azuredns: %w", err)
+	}
+
+	return nil
+}
+
+// cleanupPrivate removes the TXT record set subDomain from zone (a private DNS zone).
+func (d *DNSProvider) cleanupPrivate(ctx context.Context, zone ServiceDiscoveryZone, subDomain string) error {
+	client, err := d.clients.PrivateRecordSetsClient(zone)
+	if err != nil {
+		return fmt.Errorf("azuredns: %w", err)
+	}
+
+	if _, err := client.Delete(ctx, zone.ResourceGroup, zone.Name, armprivatedns.RecordTypeTXT, subDomain, nil); err != nil {
+		return fmt.Errorf("azuredns: %w", err)
+	}
+
+	return nil
+}
+
+// mergePublicTxtRecords returns the TXT values already in props plus value, deduplicated.
+func mergePublicTxtRecords(props *armdns.RecordSetProperties, value string) []*armdns.TxtRecord {
+	uniqueValues := map[string]struct{}{value: {}}
+	if props != nil {
+		for _, txtRecord := range props.TxtRecords {
+			if len(txtRecord.Value) > 0 {
+				uniqueValues[deref(txtRecord.Value[0])] = struct{}{}
+			}
+		}
+	}
+
+	var txtRecords []*armdns.TxtRecord
+	for txt := range uniqueValues {
+		txt := txt
+		txtRecords = append(txtRecords, &armdns.TxtRecord{Value: []*string{&txt}})
+	}
+
+	return txtRecords
+}
+
+// mergePrivateTxtRecords returns the TXT values already in props plus value, deduplicated.
+func mergePrivateTxtRecords(props *armprivatedns.RecordSetProperties, value string) []*armprivatedns.TxtRecord {
+	uniqueValues := map[string]struct{}{value: {}}
+	if props != nil {
+		for _, txtRecord := range props.TxtRecords {
+			if len(txtRecord.Value) > 0 {
+				uniqueValues[deref(txtRecord.Value[0])] = struct{}{}
+			}
+		}
+	}
+
+	var txtRecords []*armprivatedns.TxtRecord
+	for txt := range uniqueValues {
+		txt := txt
+		txtRecords = append(txtRecords, &armprivatedns.TxtRecord{Value: []*string{&txt}})
+	}
+
+	return txtRecords
+}
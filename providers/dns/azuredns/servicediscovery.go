@@ -2,18 +2,26 @@ package azuredns
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resourcegraph/armresourcegraph"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/subscription/armsubscription"
 )
 
 type ServiceDiscoveryZone struct {
 	Name           string
 	SubscriptionID string
 	ResourceGroup  string
+	TenantID       string
 }
 
 const (
@@ -29,9 +37,118 @@ resources
 
 const ResourceGraphQueryOptionsTop = 1000
 
-// discoverDnsZones finds all visible Azure DNS zones based on optional subscriptionID, resourceGroup and servicediscovery filter using Kusto query.
-func discoverDnsZones(config *Config, credentials azcore.TokenCredential) (map[string]ServiceDiscoveryZone, error) {
-	ctx := context.Background()
+// ZoneDiscoveryMethod selects how discoverDnsZones enumerates visible DNS zones.
+// It is configured via the AZURE_DNS_ZONE_DISCOVERY_METHOD environment variable.
+const (
+	// ZoneDiscoveryMethodResourceGraph queries the Azure Resource Graph API (default).
+	ZoneDiscoveryMethodResourceGraph = "resourcegraph"
+	// ZoneDiscoveryMethodListBySubscription enumerates zones directly via the DNS/Private DNS
+	// list APIs, for subscriptions where Resource Graph is restricted or unavailable (e.g. sovereign clouds).
+	ZoneDiscoveryMethodListBySubscription = "listbysubscription"
+	// ZoneDiscoveryMethodAuto tries Resource Graph first and falls back to ZoneDiscoveryMethodListBySubscription
+	// if the Resource Graph query fails with an authorization error.
+	ZoneDiscoveryMethodAuto = "auto"
+)
+
+// discoverDnsZones finds all visible Azure DNS zones based on optional subscriptionID(s), resourceGroup
+// and servicediscovery filter. config.SubscriptionID may hold a comma-separated list of subscription IDs;
+// config.SubscriptionTenants optionally overrides the tenant used for individual subscriptions (AZURE_DNS_TENANTS),
+// which matters when those subscriptions live in a different tenant than credentials was issued for.
+func discoverDnsZones(ctx context.Context, config *Config, credentials azcore.TokenCredential) (map[string]ServiceDiscoveryZone, error) {
+	switch config.ZoneDiscoveryMethod {
+	case ZoneDiscoveryMethodListBySubscription:
+		return discoverDnsZonesByList(ctx, config, credentials)
+	case ZoneDiscoveryMethodAuto:
+		zones, err := discoverDnsZonesByResourceGraph(ctx, config, credentials)
+		if err != nil && isAuthorizationError(err) {
+			return discoverDnsZonesByList(ctx, config, credentials)
+		}
+		return zones, err
+	default:
+		return discoverDnsZonesByResourceGraph(ctx, config, credentials)
+	}
+}
+
+// splitSubscriptionIDs parses a comma-separated AZURE_SUBSCRIPTION_ID value into its individual
+// subscription IDs. An empty input yields no entries, meaning "every subscription the credential can see".
+func splitSubscriptionIDs(raw string) []string {
+	var subscriptionIDs []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			subscriptionIDs = append(subscriptionIDs, part)
+		}
+	}
+
+	return subscriptionIDs
+}
+
+// tenantForSubscription returns the tenant ID to use for subscriptionID, honoring the
+// AZURE_DNS_TENANTS override map and falling back to the provider's default tenant.
+func tenantForSubscription(config *Config, subscriptionID string) string {
+	if tenantID, ok := config.SubscriptionTenants[subscriptionID]; ok && tenantID != "" {
+		return tenantID
+	}
+
+	return config.TenantID
+}
+
+// groupSubscriptionsByTenant buckets subscriptionIDs by the tenant that should be used to query them,
+// so that a single Resource Graph call can be issued per tenant scope via the Subscriptions field.
+func groupSubscriptionsByTenant(config *Config, subscriptionIDs []string) map[string][]string {
+	groups := map[string][]string{}
+	for _, subscriptionID := range subscriptionIDs {
+		tenantID := tenantForSubscription(config, subscriptionID)
+		groups[tenantID] = append(groups[tenantID], subscriptionID)
+	}
+
+	return groups
+}
+
+// isAuthorizationError returns true if err looks like the caller lacking permission
+// to query the Resource Graph API (e.g. AuthorizationFailed, 403 Forbidden).
+func isAuthorizationError(err error) bool {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		if respErr.StatusCode == 403 || respErr.ErrorCode == "AuthorizationFailed" || respErr.ErrorCode == "AuthorizationDisabled" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// discoverDnsZonesByResourceGraph finds all visible Azure DNS zones using a Resource Graph Kusto query.
+// When config.SubscriptionID lists subscriptions spread across multiple tenants (via AZURE_DNS_TENANTS),
+// one query is issued per tenant scope, each using the credential for that tenant.
+func discoverDnsZonesByResourceGraph(ctx context.Context, config *Config, credentials azcore.TokenCredential) (map[string]ServiceDiscoveryZone, error) {
+	zones := map[string]ServiceDiscoveryZone{}
+
+	subscriptionIDs := splitSubscriptionIDs(config.SubscriptionID)
+	tenantGroups := groupSubscriptionsByTenant(config, subscriptionIDs)
+	if len(tenantGroups) == 0 {
+		tenantGroups = map[string][]string{config.TenantID: nil}
+	}
+
+	for tenantID, scopedSubscriptionIDs := range tenantGroups {
+		tenantZones, err := discoverDnsZonesByResourceGraphScope(ctx, config, tenantCredential(config, credentials, tenantID), tenantID, scopedSubscriptionIDs)
+		if err != nil {
+			return zones, err
+		}
+
+		for key, zone := range tenantZones {
+			if _, exists := zones[key]; exists {
+				return zones, fmt.Errorf(`found duplicate dns zone "%s"`, key)
+			}
+			zones[key] = zone
+		}
+	}
+
+	return zones, nil
+}
+
+// discoverDnsZonesByResourceGraphScope runs a single Resource Graph query scoped to subscriptionIDs
+// (via the request's Subscriptions field) using credentials issued for tenantID.
+func discoverDnsZonesByResourceGraphScope(ctx context.Context, config *Config, credentials azcore.TokenCredential, tenantID string, subscriptionIDs []string) (map[string]ServiceDiscoveryZone, error) {
 	zones := map[string]ServiceDiscoveryZone{}
 
 	resourceType := ResourceGraphTypePublicDnsZone
@@ -40,13 +157,6 @@ func discoverDnsZones(config *Config, credentials azcore.TokenCredential) (map[s
 	}
 
 	resourceGraphConditions := []string{}
-	// subscriptionID filter
-	if config.SubscriptionID != "" {
-		resourceGraphConditions = append(
-			resourceGraphConditions,
-			fmt.Sprintf(`| where subscriptionId =~ "%s"`, config.SubscriptionID),
-		)
-	}
 	// resourceGroup filter
 	if config.ResourceGroup != "" {
 		resourceGraphConditions = append(
@@ -90,14 +200,20 @@ func discoverDnsZones(config *Config, credentials azcore.TokenCredential) (map[s
 		Skip:         &requestQuerySkip,
 	}
 
+	var subscriptions []*string
+	for i := range subscriptionIDs {
+		subscriptions = append(subscriptions, &subscriptionIDs[i])
+	}
+
 	for {
-		// create the query request
+		// create the query request, scoped to this tenant's subscriptions (if any were given)
 		request := armresourcegraph.QueryRequest{
-			Query:   &resourceGraphQuery,
-			Options: &requestOptions,
+			Query:         &resourceGraphQuery,
+			Options:       &requestOptions,
+			Subscriptions: subscriptions,
 		}
 
-		var result, queryErr = resourceGraphClient.Resources(ctx, request, nil)
+		result, queryErr := queryResourceGraphWithRetry(ctx, resourceGraphClient, request)
 		if queryErr != nil {
 			return zones, queryErr
 		}
@@ -106,15 +222,19 @@ func discoverDnsZones(config *Config, credentials azcore.TokenCredential) (map[s
 			for _, row := range resultList {
 				if rowData, ok := row.(map[string]interface{}); ok {
 					if zoneName, ok := rowData["name"].(string); ok {
-						if _, exists := zones[zoneName]; exists {
-							return zones, fmt.Errorf(`found duplicate dns zone "%s"`, zoneName)
-						}
-
-						zones[zoneName] = ServiceDiscoveryZone{
+						zone := ServiceDiscoveryZone{
 							Name:           zoneName,
 							ResourceGroup:  rowData["resourceGroup"].(string),
 							SubscriptionID: rowData["subscriptionId"].(string),
+							TenantID:       tenantID,
+						}
+
+						key := zoneKey(zone)
+						if _, exists := zones[key]; exists {
+							return zones, fmt.Errorf(`found duplicate dns zone "%s"`, key)
 						}
+
+						zones[key] = zone
 					}
 				}
 			}
@@ -133,3 +253,334 @@ func discoverDnsZones(config *Config, credentials azcore.TokenCredential) (map[s
 
 	return zones, nil
 }
+
+const (
+	resourceGraphMaxAttempts    = 5
+	resourceGraphBaseRetryDelay = 1 * time.Second
+	resourceGraphMaxRetryDelay  = 30 * time.Second
+)
+
+// queryResourceGraphWithRetry calls client.Resources with exponential-backoff retry on HTTP 429,
+// since Resource Graph is heavily throttled (15 requests/5s per tenant). A 429 response's
+// Retry-After header, when present, takes precedence over the computed backoff.
+func queryResourceGraphWithRetry(ctx context.Context, client *armresourcegraph.Client, request armresourcegraph.QueryRequest) (armresourcegraph.ClientResourcesResponse, error) {
+	delay := resourceGraphBaseRetryDelay
+
+	var lastErr error
+	for attempt := 0; attempt < resourceGraphMaxAttempts; attempt++ {
+		result, err := client.Resources(ctx, request, nil)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+
+		wait, retryable := resourceGraphRetryDelay(err, delay)
+		if !retryable {
+			return result, err
+		}
+
+		if attempt == resourceGraphMaxAttempts-1 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(wait):
+		}
+
+		delay *= 2
+	}
+
+	return armresourcegraph.ClientResourcesResponse{}, lastErr
+}
+
+// resourceGraphRetryDelay inspects err for an HTTP 429 response and returns how long to wait before
+// retrying: the response's Retry-After header (in seconds) if set, otherwise the caller-supplied
+// backoff, either one capped at resourceGraphMaxRetryDelay.
+func resourceGraphRetryDelay(err error, backoff time.Duration) (time.Duration, bool) {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != 429 {
+		return 0, false
+	}
+
+	if respErr.RawResponse != nil {
+		if retryAfter := respErr.RawResponse.Header.Get("Retry-After"); retryAfter != "" {
+			if seconds, convErr := strconv.Atoi(retryAfter); convErr == nil {
+				return min(time.Duration(seconds)*time.Second, resourceGraphMaxRetryDelay), true
+			}
+		}
+	}
+
+	return min(backoff, resourceGraphMaxRetryDelay), true
+}
+
+// zoneKey builds the fully-qualified map key for zone, so that the same zone name legitimately
+// present in two different subscriptions or resource groups does not collide.
+func zoneKey(zone ServiceDiscoveryZone) string {
+	return fmt.Sprintf("%s/%s/%s", zone.SubscriptionID, zone.ResourceGroup, zone.Name)
+}
+
+// tenantCredential returns the credential to use for tenantID: the explicit per-tenant override from
+// config.TenantCredentials if one is registered (typically populated by the provider from AZURE_DNS_TENANTS),
+// otherwise the default credential shared by every tenant.
+func tenantCredential(config *Config, defaultCredential azcore.TokenCredential, tenantID string) azcore.TokenCredential {
+	if credential, ok := config.TenantCredentials[tenantID]; ok && credential != nil {
+		return credential
+	}
+
+	return defaultCredential
+}
+
+// discoverDnsZonesByList finds all visible Azure DNS zones using the native armdns/armprivatedns
+// list APIs instead of Resource Graph. It is used when Resource Graph is unavailable (sovereign/
+// air-gapped clouds) or explicitly restricted, and iterates every subscription the credential can
+// see unless config.SubscriptionID narrows the scope.
+func discoverDnsZonesByList(ctx context.Context, config *Config, credentials azcore.TokenCredential) (map[string]ServiceDiscoveryZone, error) {
+	zones := map[string]ServiceDiscoveryZone{}
+
+	options := arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Cloud: config.Environment,
+		},
+	}
+
+	subscriptionIDs, err := resolveSubscriptionIDs(ctx, config, credentials, &options)
+	if err != nil {
+		return zones, err
+	}
+
+	filterKey, filterValue := parseTagFilter(config.ServiceDiscoveryFilter)
+
+	for _, subscriptionID := range subscriptionIDs {
+		tenantID := tenantForSubscription(config, subscriptionID)
+		credentials := tenantCredential(config, credentials, tenantID)
+
+		if config.PrivateZone {
+			client, clientErr := armprivatedns.NewPrivateZonesClient(subscriptionID, credentials, &options)
+			if clientErr != nil {
+				return zones, clientErr
+			}
+
+			pager := client.NewListPager(nil)
+			for pager.More() {
+				page, pageErr := pager.NextPage(ctx)
+				if pageErr != nil {
+					return zones, pageErr
+				}
+
+				for _, zone := range page.Value {
+					if zone.Name == nil {
+						continue
+					}
+
+					resourceGroup := resourceGroupFromID(*zone.ID)
+					if config.ResourceGroup != "" && !strings.EqualFold(resourceGroup, config.ResourceGroup) {
+						continue
+					}
+					if !matchesTagFilter(zone.Tags, filterKey, filterValue) {
+						continue
+					}
+
+					zone := ServiceDiscoveryZone{
+						Name:           *zone.Name,
+						ResourceGroup:  resourceGroup,
+						SubscriptionID: subscriptionID,
+						TenantID:       tenantID,
+					}
+					zones[zoneKey(zone)] = zone
+				}
+			}
+		} else {
+			client, clientErr := armdns.NewZonesClient(subscriptionID, credentials, &options)
+			if clientErr != nil {
+				return zones, clientErr
+			}
+
+			pager := client.NewListPager(nil)
+			for pager.More() {
+				page, pageErr := pager.NextPage(ctx)
+				if pageErr != nil {
+					return zones, pageErr
+				}
+
+				for _, zone := range page.Value {
+					if zone.Name == nil {
+						continue
+					}
+
+					resourceGroup := resourceGroupFromID(*zone.ID)
+					if config.ResourceGroup != "" && !strings.EqualFold(resourceGroup, config.ResourceGroup) {
+						continue
+					}
+					if !matchesTagFilter(zone.Tags, filterKey, filterValue) {
+						continue
+					}
+
+					zone := ServiceDiscoveryZone{
+						Name:           *zone.Name,
+						ResourceGroup:  resourceGroup,
+						SubscriptionID: subscriptionID,
+						TenantID:       tenantID,
+					}
+					zones[zoneKey(zone)] = zone
+				}
+			}
+		}
+	}
+
+	return zones, nil
+}
+
+// resolveSubscriptionIDs returns the configured subscription ID(s), or, if none are set, every
+// subscription visible to credentials via the Azure Subscriptions API.
+//
+// Note: the Azure SDK module for this API is "sdk/resourcemanager/subscription/armsubscription"
+// (singular) exposing armsubscription.NewSubscriptionsClient - there is no "armsubscriptions"
+// (plural) module published for go-acme/lego's pinned SDK line.
+func resolveSubscriptionIDs(ctx context.Context, config *Config, credentials azcore.TokenCredential, options *arm.ClientOptions) ([]string, error) {
+	if subscriptionIDs := splitSubscriptionIDs(config.SubscriptionID); len(subscriptionIDs) > 0 {
+		return subscriptionIDs, nil
+	}
+
+	client, err := armsubscription.NewSubscriptionsClient(credentials, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscriptionIDs []string
+	pager := client.NewListPager(nil)
+	for pager.More() {
+		page, pageErr := pager.NextPage(ctx)
+		if pageErr != nil {
+			return nil, pageErr
+		}
+
+		for _, subscription := range page.Value {
+			if subscription.SubscriptionID != nil {
+				subscriptionIDs = append(subscriptionIDs, *subscription.SubscriptionID)
+			}
+		}
+	}
+
+	return subscriptionIDs, nil
+}
+
+// resourceGroupFromID extracts the resource group segment from an Azure resource ID.
+func resourceGroupFromID(id string) string {
+	parts := strings.Split(id, "/")
+	for i, part := range parts {
+		if strings.EqualFold(part, "resourceGroups") && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+
+	return ""
+}
+
+// parseTagFilter reinterprets config.ServiceDiscoveryFilter as a "key=value" tag filter for use with
+// the list-based discovery methods, where a Kusto filter clause (used by the Resource Graph path)
+// cannot be applied. The same config value can reach both paths via ZoneDiscoveryMethodAuto's
+// fallback, so a string that doesn't look like a plain "key=value" pair (e.g. a Kusto "where ..."
+// clause) is treated as unset rather than misparsed into a key/value pair that matches nothing.
+func parseTagFilter(filter string) (key, value string) {
+	if filter == "" || strings.ContainsAny(filter, "|'\"") {
+		return "", ""
+	}
+
+	parts := strings.SplitN(filter, "=", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+
+	key, value = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	if key == "" || strings.ContainsAny(key, " \t") {
+		return "", ""
+	}
+
+	return key, value
+}
+
+// matchesTagFilter returns true if filterKey is empty, or tags contains filterKey with filterValue.
+func matchesTagFilter(tags map[string]*string, filterKey, filterValue string) bool {
+	if filterKey == "" {
+		return true
+	}
+
+	value, ok := tags[filterKey]
+	if !ok || value == nil {
+		return false
+	}
+
+	return *value == filterValue
+}
+
+// dnsZoneClientCache lazily builds and caches one armdns.RecordSetsClient/armprivatedns.RecordSetsClient
+// per (subscriptionID, tenantID) pair, so that Present/CleanUp can route each ACME challenge to the
+// client matching the zone discoverDnsZones resolved it from, rather than assuming a single subscription.
+type dnsZoneClientCache struct {
+	config      *Config
+	credentials azcore.TokenCredential
+
+	mu                sync.Mutex
+	recordSetClients  map[string]*armdns.RecordSetsClient
+	privateRecordSets map[string]*armprivatedns.RecordSetsClient
+}
+
+// newDnsZoneClientCache creates a client cache that falls back to credentials for any zone whose
+// tenant has no override registered in config.TenantCredentials.
+func newDnsZoneClientCache(config *Config, credentials azcore.TokenCredential) *dnsZoneClientCache {
+	return &dnsZoneClientCache{
+		config:            config,
+		credentials:       credentials,
+		recordSetClients:  map[string]*armdns.RecordSetsClient{},
+		privateRecordSets: map[string]*armprivatedns.RecordSetsClient{},
+	}
+}
+
+// cacheKey builds the (subscriptionID, tenantID) map key for zone.
+func (c *dnsZoneClientCache) cacheKey(zone ServiceDiscoveryZone) string {
+	return zone.SubscriptionID + "/" + zone.TenantID
+}
+
+// RecordSetsClient returns the armdns.RecordSetsClient for zone's subscription and tenant, creating and caching it on first use.
+func (c *dnsZoneClientCache) RecordSetsClient(zone ServiceDiscoveryZone) (*armdns.RecordSetsClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := c.cacheKey(zone)
+	if client, ok := c.recordSetClients[key]; ok {
+		return client, nil
+	}
+
+	options := arm.ClientOptions{ClientOptions: azcore.ClientOptions{Cloud: c.config.Environment}}
+	client, err := armdns.NewRecordSetsClient(zone.SubscriptionID, tenantCredential(c.config, c.credentials, zone.TenantID), &options)
+	if err != nil {
+		return nil, err
+	}
+
+	c.recordSetClients[key] = client
+
+	return client, nil
+}
+
+// PrivateRecordSetsClient returns the armprivatedns.RecordSetsClient for zone's subscription and tenant, creating and caching it on first use.
+func (c *dnsZoneClientCache) PrivateRecordSetsClient(zone ServiceDiscoveryZone) (*armprivatedns.RecordSetsClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := c.cacheKey(zone)
+	if client, ok := c.privateRecordSets[key]; ok {
+		return client, nil
+	}
+
+	options := arm.ClientOptions{ClientOptions: azcore.ClientOptions{Cloud: c.config.Environment}}
+	client, err := armprivatedns.NewRecordSetsClient(zone.SubscriptionID, tenantCredential(c.config, c.credentials, zone.TenantID), &options)
+	if err != nil {
+		return nil, err
+	}
+
+	c.privateRecordSets[key] = client
+
+	return client, nil
+}
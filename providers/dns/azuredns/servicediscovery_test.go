@@ -0,0 +1,266 @@
+package azuredns
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+func TestParseTagFilter(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		filter    string
+		wantKey   string
+		wantValue string
+	}{
+		{
+			desc:      "empty filter",
+			filter:    "",
+			wantKey:   "",
+			wantValue: "",
+		},
+		{
+			desc:      "plain key=value",
+			filter:    "environment=production",
+			wantKey:   "environment",
+			wantValue: "production",
+		},
+		{
+			desc:      "trims whitespace around key and value",
+			filter:    "  environment  =  production  ",
+			wantKey:   "environment",
+			wantValue: "production",
+		},
+		{
+			desc:      "missing equals sign is unset",
+			filter:    "environment",
+			wantKey:   "",
+			wantValue: "",
+		},
+		{
+			desc:      "kusto clause is unset, not misparsed",
+			filter:    `resourceGroup =~ "dns-prod"`,
+			wantKey:   "",
+			wantValue: "",
+		},
+		{
+			desc:      "key containing whitespace is unset",
+			filter:    "resourceGroup contains 'dns' = true",
+			wantKey:   "",
+			wantValue: "",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			key, value := parseTagFilter(test.filter)
+			if key != test.wantKey || value != test.wantValue {
+				t.Errorf("parseTagFilter(%q) = (%q, %q), want (%q, %q)", test.filter, key, value, test.wantKey, test.wantValue)
+			}
+		})
+	}
+}
+
+func TestMatchesTagFilter(t *testing.T) {
+	value := "production"
+
+	testCases := []struct {
+		desc        string
+		tags        map[string]*string
+		filterKey   string
+		filterValue string
+		want        bool
+	}{
+		{
+			desc:      "no filter matches everything",
+			tags:      nil,
+			filterKey: "",
+			want:      true,
+		},
+		{
+			desc:        "matching tag",
+			tags:        map[string]*string{"environment": &value},
+			filterKey:   "environment",
+			filterValue: "production",
+			want:        true,
+		},
+		{
+			desc:        "tag value mismatch",
+			tags:        map[string]*string{"environment": &value},
+			filterKey:   "environment",
+			filterValue: "staging",
+			want:        false,
+		},
+		{
+			desc:        "tag missing",
+			tags:        map[string]*string{},
+			filterKey:   "environment",
+			filterValue: "production",
+			want:        false,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			got := matchesTagFilter(test.tags, test.filterKey, test.filterValue)
+			if got != test.want {
+				t.Errorf("matchesTagFilter() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTenantForSubscription(t *testing.T) {
+	config := &Config{
+		TenantID:            "default-tenant",
+		SubscriptionTenants: map[string]string{"sub1": "tenant1"},
+	}
+
+	testCases := []struct {
+		desc           string
+		subscriptionID string
+		want           string
+	}{
+		{
+			desc:           "subscription with a tenant override",
+			subscriptionID: "sub1",
+			want:           "tenant1",
+		},
+		{
+			desc:           "subscription without an override falls back to the default tenant",
+			subscriptionID: "sub2",
+			want:           "default-tenant",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			got := tenantForSubscription(config, test.subscriptionID)
+			if got != test.want {
+				t.Errorf("tenantForSubscription() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGroupSubscriptionsByTenant(t *testing.T) {
+	config := &Config{
+		TenantID:            "default-tenant",
+		SubscriptionTenants: map[string]string{"sub1": "tenant1", "sub2": "tenant1"},
+	}
+
+	groups := groupSubscriptionsByTenant(config, []string{"sub1", "sub2", "sub3"})
+
+	if len(groups) != 2 {
+		t.Fatalf("got %d tenant groups, want 2: %+v", len(groups), groups)
+	}
+
+	wantTenant1 := []string{"sub1", "sub2"}
+	if got := groups["tenant1"]; !equalStringSlices(got, wantTenant1) {
+		t.Errorf(`groups["tenant1"] = %v, want %v`, got, wantTenant1)
+	}
+
+	wantDefaultTenant := []string{"sub3"}
+	if got := groups["default-tenant"]; !equalStringSlices(got, wantDefaultTenant) {
+		t.Errorf(`groups["default-tenant"] = %v, want %v`, got, wantDefaultTenant)
+	}
+}
+
+func equalStringSlices(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDnsZoneClientCacheKey(t *testing.T) {
+	cache := newDnsZoneClientCache(&Config{}, nil)
+
+	testCases := []struct {
+		desc string
+		a, b ServiceDiscoveryZone
+		want bool
+	}{
+		{
+			desc: "same subscription and tenant collide",
+			a:    ServiceDiscoveryZone{SubscriptionID: "sub1", TenantID: "tenant1"},
+			b:    ServiceDiscoveryZone{SubscriptionID: "sub1", TenantID: "tenant1"},
+			want: true,
+		},
+		{
+			desc: "same subscription, different tenant do not collide",
+			a:    ServiceDiscoveryZone{SubscriptionID: "sub1", TenantID: "tenant1"},
+			b:    ServiceDiscoveryZone{SubscriptionID: "sub1", TenantID: "tenant2"},
+			want: false,
+		},
+		{
+			desc: "different subscription, same tenant do not collide",
+			a:    ServiceDiscoveryZone{SubscriptionID: "sub1", TenantID: "tenant1"},
+			b:    ServiceDiscoveryZone{SubscriptionID: "sub2", TenantID: "tenant1"},
+			want: false,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			got := cache.cacheKey(test.a) == cache.cacheKey(test.b)
+			if got != test.want {
+				t.Errorf("cacheKey equality = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResourceGraphRetryDelay(t *testing.T) {
+	backoff := 2 * time.Second
+
+	t.Run("non-429 error is not retryable", func(t *testing.T) {
+		_, retryable := resourceGraphRetryDelay(errors.New("boom"), backoff)
+		if retryable {
+			t.Error("expected a plain error to not be retryable")
+		}
+	})
+
+	t.Run("429 without Retry-After uses backoff", func(t *testing.T) {
+		err := &azcore.ResponseError{StatusCode: http.StatusTooManyRequests}
+		wait, retryable := resourceGraphRetryDelay(err, backoff)
+		if !retryable {
+			t.Fatal("expected a 429 to be retryable")
+		}
+		if wait != backoff {
+			t.Errorf("wait = %v, want %v", wait, backoff)
+		}
+	})
+
+	t.Run("429 with Retry-After overrides backoff", func(t *testing.T) {
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{"5"}}}
+		err := &azcore.ResponseError{StatusCode: http.StatusTooManyRequests, RawResponse: resp}
+		wait, retryable := resourceGraphRetryDelay(err, backoff)
+		if !retryable {
+			t.Fatal("expected a 429 to be retryable")
+		}
+		if wait != 5*time.Second {
+			t.Errorf("wait = %v, want %v", wait, 5*time.Second)
+		}
+	})
+
+	t.Run("Retry-After is capped at resourceGraphMaxRetryDelay", func(t *testing.T) {
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{"3600"}}}
+		err := &azcore.ResponseError{StatusCode: http.StatusTooManyRequests, RawResponse: resp}
+		wait, retryable := resourceGraphRetryDelay(err, backoff)
+		if !retryable {
+			t.Fatal("expected a 429 to be retryable")
+		}
+		if wait != resourceGraphMaxRetryDelay {
+			t.Errorf("wait = %v, want %v", wait, resourceGraphMaxRetryDelay)
+		}
+	})
+}